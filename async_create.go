@@ -0,0 +1,61 @@
+package pool
+
+// asyncCreator drains creation requests on a small pool of background
+// goroutines, so a slow factory.MakeObject never blocks the borrower that
+// triggered it. A successfully created object is handed to offerIdle, the
+// same path ReturnObject and ensureIdle use, so a waiting borrower (fair or
+// not) picks it up exactly as if another goroutine had returned it.
+type asyncCreator struct {
+	pool     *ObjectPool
+	requests chan struct{}
+	stop     chan struct{}
+}
+
+// newAsyncCreator starts concurrency worker goroutines (at least 1) that run
+// until Stop is called.
+func newAsyncCreator(pool *ObjectPool, concurrency int) *asyncCreator {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	this := &asyncCreator{
+		pool: pool,
+		// Buffered to concurrency: a pending request per worker is enough
+		// to keep all workers busy; anything beyond that means a creation
+		// is already in flight or about to be, so further bursts coalesce
+		// into it instead of queuing up.
+		requests: make(chan struct{}, concurrency),
+		stop:     make(chan struct{}),
+	}
+	for i := 0; i < concurrency; i++ {
+		go this.worker()
+	}
+	return this
+}
+
+func (this *asyncCreator) worker() {
+	for {
+		select {
+		case <-this.requests:
+			p := this.pool.create()
+			if p != nil {
+				this.pool.offerIdle(p)
+			}
+		case <-this.stop:
+			return
+		}
+	}
+}
+
+// requestCreate enqueues a creation request. If CreateConcurrency requests
+// are already pending, this call is a no-op: the burst that triggered it is
+// already covered by creations in flight.
+func (this *asyncCreator) requestCreate() {
+	select {
+	case this.requests <- struct{}{}:
+	default:
+	}
+}
+
+func (this *asyncCreator) Stop() {
+	close(this.stop)
+}
@@ -0,0 +1,106 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingFactory's MakeObject blocks until released is closed, so tests can
+// control exactly how many creations are in flight at once.
+type blockingFactory struct {
+	released chan struct{}
+	started  int64
+	created  int64
+}
+
+func newBlockingFactory() *blockingFactory {
+	return &blockingFactory{released: make(chan struct{})}
+}
+
+func (this *blockingFactory) MakeObject() (*PooledObject, error) {
+	atomic.AddInt64(&this.started, 1)
+	<-this.released
+	n := atomic.AddInt64(&this.created, 1)
+	return NewPooledObject(&n), nil
+}
+
+func (this *blockingFactory) ActivateObject(object *PooledObject) error  { return nil }
+func (this *blockingFactory) PassivateObject(object *PooledObject) error { return nil }
+func (this *blockingFactory) ValidateObject(object *PooledObject) bool   { return true }
+func (this *blockingFactory) DestroyObject(object *PooledObject) error   { return nil }
+
+// TestAsyncCreatorCoalescesBurst checks that requestCreate coalesces a burst
+// of requests beyond CreateConcurrency into the creations already pending,
+// instead of queuing one worker invocation per request: with concurrency 2
+// and MakeObject blocked, firing many requestCreate calls must not start
+// more than 2 concurrent MakeObject calls.
+func TestAsyncCreatorCoalescesBurst(t *testing.T) {
+	factory := newBlockingFactory()
+	config := NewDefaultPoolConfig()
+	config.AsyncCreate = true
+	config.CreateConcurrency = 2
+	p := NewObjectPool(factory, config)
+	defer p.Close()
+
+	const burst = 50
+	for i := 0; i < burst; i++ {
+		p.asyncCreator.requestCreate()
+	}
+
+	// Give the workers a moment to pick up whatever they're going to pick
+	// up; with only 2 workers and MakeObject blocked, at most 2 should ever
+	// have started.
+	deadline := time.After(time.Second)
+	for {
+		if atomic.LoadInt64(&factory.started) >= int64(config.CreateConcurrency) {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("workers never reached CreateConcurrency=%d in-flight creations (got %d)",
+				config.CreateConcurrency, atomic.LoadInt64(&factory.started))
+		case <-time.After(time.Millisecond):
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	if started := atomic.LoadInt64(&factory.started); started != int64(config.CreateConcurrency) {
+		t.Fatalf("expected exactly CreateConcurrency=%d MakeObject calls in flight, got %d",
+			config.CreateConcurrency, started)
+	}
+
+	close(factory.released)
+}
+
+// TestAsyncCreatorCreatedObjectsReachIdle checks that objects produced by
+// async creation workers end up available to borrow, the same as if they
+// had been created synchronously.
+func TestAsyncCreatorCreatedObjectsReachIdle(t *testing.T) {
+	factory := &testFactory{}
+	config := NewDefaultPoolConfig()
+	config.AsyncCreate = true
+	config.CreateConcurrency = 4
+	config.MaxTotal = 4
+	p := NewObjectPool(factory, config)
+	defer p.Close()
+
+	var wg sync.WaitGroup
+	results := make(chan error, config.MaxTotal)
+	for i := 0; i < config.MaxTotal; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.BorrowObject()
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	for err := range results {
+		if err != nil {
+			t.Fatalf("expected all borrows to eventually succeed via async creation, got %v", err)
+		}
+	}
+}
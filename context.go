@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// takeFirstWithContext waits for an idle object via idleObjects.TakeFirst,
+// unblocking as soon as ctx is done. LinkedBlockingDeque has no native way
+// to cancel a blocked TakeFirst, so the blocking call is run on its own
+// goroutine and raced against ctx.Done(); if ctx wins, that goroutine is
+// left running in the background and, if TakeFirst eventually does return
+// an object, the object is handed to offerIdle instead of being silently
+// dropped.
+func (this *ObjectPool) takeFirstWithContext(ctx context.Context) (interface{}, error) {
+	objCh := make(chan interface{}, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		obj, err := this.idleObjects.TakeFirst()
+		if err != nil {
+			errCh <- err
+			return
+		}
+		objCh <- obj
+	}()
+
+	select {
+	case obj := <-objCh:
+		return obj, nil
+	case err := <-errCh:
+		return nil, err
+	case <-ctx.Done():
+		go func() {
+			select {
+			case obj := <-objCh:
+				if p, ok := obj.(*PooledObject); ok {
+					this.offerIdle(p)
+				}
+			case <-errCh:
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// pollFirstWithContext is the bounded-wait counterpart of
+// takeFirstWithContext, racing idleObjects.PollFirstWithTimeout(timeout)
+// against ctx.Done() the same way.
+func (this *ObjectPool) pollFirstWithContext(ctx context.Context, timeout time.Duration) (interface{}, error) {
+	objCh := make(chan interface{}, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		obj, err := this.idleObjects.PollFirstWithTimeout(timeout)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		objCh <- obj
+	}()
+
+	select {
+	case obj := <-objCh:
+		return obj, nil
+	case err := <-errCh:
+		return nil, err
+	case <-ctx.Done():
+		go func() {
+			select {
+			case obj := <-objCh:
+				if p, ok := obj.(*PooledObject); ok {
+					this.offerIdle(p)
+				}
+			case <-errCh:
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// ReturnObjectWithContext is equivalent to ReturnObject, except it first
+// checks ctx so a caller that gave up waiting elsewhere does not also block
+// returning its object. ReturnObject itself never waits on idle object
+// availability, so ctx only guards against an already-done context.
+func (this *ObjectPool) ReturnObjectWithContext(ctx context.Context, object interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return this.ReturnObject(object)
+}
+
+// InvalidateObjectWithContext is the context-aware counterpart of
+// InvalidateObject.
+func (this *ObjectPool) InvalidateObjectWithContext(ctx context.Context, object interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return this.InvalidateObject(object)
+}
@@ -0,0 +1,150 @@
+package pool
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// fairWaiter is a single borrower's ticket in the FIFO waiter queue. ch is
+// buffered with capacity 1 so fifoWaiterQueue.offer never blocks handing an
+// object off, even if the waiter has since timed out and stopped listening.
+type fairWaiter struct {
+	ch   chan *PooledObject
+	elem *list.Element
+}
+
+// fifoWaiterQueue serializes borrowers waiting on an exhausted pool into
+// strict arrival order. It replaces relying on sync.Cond's Broadcast, which
+// gives Go's runtime no ordering guarantee and can starve an unlucky waiter
+// under constant borrow/return churn.
+type fifoWaiterQueue struct {
+	lock    sync.Mutex
+	waiters *list.List
+}
+
+func newFifoWaiterQueue() *fifoWaiterQueue {
+	return &fifoWaiterQueue{waiters: list.New()}
+}
+
+// enqueue joins the back of the queue and returns the waiter's ticket. The
+// caller must eventually call remove, whether or not it received an object.
+func (this *fifoWaiterQueue) enqueue() *fairWaiter {
+	w := &fairWaiter{ch: make(chan *PooledObject, 1)}
+	this.lock.Lock()
+	w.elem = this.waiters.PushBack(w)
+	this.lock.Unlock()
+	return w
+}
+
+// cancel takes w out of the queue under the same lock offer uses, so
+// abandoning a wait and handing it an object can never race: if cancel
+// still finds w in the queue, it removes it and no offer will ever reach it
+// (claimed reports false). If offer already removed w before cancel got the
+// lock, offer's send into w.ch happened-before that removal was visible
+// here, so the object is already waiting in the buffered channel and
+// claimed reports true - the caller must then receive from w.ch, which will
+// not block.
+func (this *fifoWaiterQueue) cancel(w *fairWaiter) (claimed bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if w.elem == nil {
+		return true
+	}
+	this.waiters.Remove(w.elem)
+	w.elem = nil
+	return false
+}
+
+// offer hands p to the longest-waiting ticket still in the queue, skipping
+// over any ticket whose channel send would not succeed immediately (it was
+// concurrently claimed by something other than this call - cancel never
+// sends, so today this loop never actually iterates more than once, but it
+// keeps offer from ever dropping p instead of delivering or falling back to
+// idleObjects). It returns false, leaving p untouched, if no waiter takes
+// it.
+func (this *fifoWaiterQueue) offer(p *PooledObject) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for {
+		front := this.waiters.Front()
+		if front == nil {
+			return false
+		}
+		this.waiters.Remove(front)
+		w := front.Value.(*fairWaiter)
+		w.elem = nil
+		select {
+		case w.ch <- p:
+			return true
+		default:
+			continue
+		}
+	}
+}
+
+// waitFair blocks until either an object is handed to this borrower's
+// ticket, ctx is done, or borrowMaxWaitMillis elapses (a negative value
+// means wait indefinitely), mirroring the semantics of
+// LinkedBlockingDeque.TakeFirst/PollFirstWithTimeout but with FIFO
+// ordering. Giving up (ctx/timeout) always goes through
+// fifoWaiterQueue.cancel so a concurrent offer can never be dropped - see
+// cancel's doc comment.
+func (this *ObjectPool) waitFair(ctx context.Context, borrowMaxWaitMillis int64) (*PooledObject, error) {
+	w := this.fairWaiters.enqueue()
+
+	// The caller already found idleObjects empty before deciding to wait,
+	// but that check happened before w joined the queue: an object
+	// returned in between would have found no waiter and been pushed onto
+	// idleObjects instead, where a fair waiter would never look again.
+	// Re-check now that w is enqueued - any offer from this point on is
+	// guaranteed to see w and deliver through w.ch instead.
+	if obj, ok := this.idleObjects.PollFirst().(*PooledObject); ok {
+		if this.fairWaiters.cancel(w) {
+			// A concurrent offer claimed w's ticket before cancel could
+			// remove it, so w.ch already holds a different object - we
+			// can't use both. Give this one back and take the delivered
+			// one instead.
+			this.offerIdle(obj)
+			return <-w.ch, nil
+		}
+		return obj, nil
+	}
+
+	var timeout <-chan time.Time
+	if borrowMaxWaitMillis >= 0 {
+		timer := time.NewTimer(time.Duration(borrowMaxWaitMillis) * time.Millisecond)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case p := <-w.ch:
+		return p, nil
+	case <-ctx.Done():
+		if this.fairWaiters.cancel(w) {
+			return <-w.ch, nil
+		}
+		return nil, ctx.Err()
+	case <-timeout:
+		if this.fairWaiters.cancel(w) {
+			return <-w.ch, nil
+		}
+		return nil, NewNoSuchElementErr("Timeout waiting for idle object")
+	}
+}
+
+// offerIdle makes p available for borrowing again: if Fairness is on and a
+// borrower is already waiting, p is handed to it directly; otherwise it is
+// pushed onto idleObjects as usual.
+func (this *ObjectPool) offerIdle(p *PooledObject) {
+	if this.Config.Fairness && this.fairWaiters.offer(p) {
+		return
+	}
+	if this.Config.Lifo {
+		this.idleObjects.AddFirst(p)
+	} else {
+		this.idleObjects.AddLast(p)
+	}
+}
@@ -0,0 +1,204 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testFactory is a minimal PooledObjectFactory that hands out sequentially
+// numbered values and never fails or invalidates anything, so tests only
+// have to reason about the pool's own bookkeeping.
+type testFactory struct {
+	created int64
+}
+
+func (this *testFactory) MakeObject() (*PooledObject, error) {
+	n := atomic.AddInt64(&this.created, 1)
+	value := fmt.Sprintf("obj-%d", n)
+	return NewPooledObject(&value), nil
+}
+
+func (this *testFactory) ActivateObject(object *PooledObject) error  { return nil }
+func (this *testFactory) PassivateObject(object *PooledObject) error { return nil }
+func (this *testFactory) ValidateObject(object *PooledObject) bool   { return true }
+func (this *testFactory) DestroyObject(object *PooledObject) error   { return nil }
+
+// TestWaitFairSeesObjectPushedBetweenPollAndEnqueue reproduces the missed
+// wakeup a fair waiter used to be vulnerable to: borrowObject's initial
+// idleObjects.PollFirst() happens before waitFair's fairWaiters.enqueue(),
+// so an object offered in that window found no waiter and was pushed onto
+// idleObjects, where a waiter selecting only on its own channel would never
+// see it. Simulating that exact push-before-enqueue ordering directly
+// (bypassing borrowObject) checks that waitFair re-checks idleObjects after
+// joining the queue instead of hanging forever.
+func TestWaitFairSeesObjectPushedBetweenPollAndEnqueue(t *testing.T) {
+	factory := &testFactory{}
+	config := NewDefaultPoolConfig()
+	config.Fairness = true
+	p := NewObjectPool(factory, config)
+
+	po, err := factory.MakeObject()
+	if err != nil {
+		t.Fatalf("MakeObject failed: %v", err)
+	}
+	p.idleObjects.AddLast(po)
+
+	done := make(chan *PooledObject, 1)
+	go func() {
+		result, err := p.waitFair(context.Background(), -1)
+		if err != nil {
+			return
+		}
+		done <- result
+	}()
+
+	select {
+	case result := <-done:
+		if result != po {
+			t.Fatalf("expected waitFair to return the object already sitting in idleObjects")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waitFair hung instead of noticing the idle object already available")
+	}
+}
+
+// TestFairnessFIFOOrder checks that, with Fairness enabled, waiters are
+// served strictly in the order they joined the queue, not in whatever order
+// Go's runtime happens to schedule them. Only one object ever exists
+// (MaxTotal=1), and each waiter returns it immediately upon receiving it, so
+// the object passes down the FIFO queue like a baton: the order it is
+// handed off in is exactly the order recorded in `order`.
+func TestFairnessFIFOOrder(t *testing.T) {
+	factory := &testFactory{}
+	config := NewDefaultPoolConfig()
+	config.MaxTotal = 1
+	config.Fairness = true
+	p := NewObjectPool(factory, config)
+
+	held, err := p.BorrowObject()
+	if err != nil {
+		t.Fatalf("initial borrow failed: %v", err)
+	}
+
+	const waiters = 5
+	order := make(chan int, waiters)
+	for i := 0; i < waiters; i++ {
+		i := i
+		go func() {
+			obj, err := p.BorrowObject()
+			if err != nil {
+				return
+			}
+			order <- i
+			p.ReturnObject(obj)
+		}()
+		// Give each waiter's goroutine a chance to reach waitFair and join
+		// the queue before starting the next, so enqueue order is
+		// deterministic.
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if err := p.ReturnObject(held); err != nil {
+		t.Fatalf("ReturnObject failed: %v", err)
+	}
+	for i := 0; i < waiters; i++ {
+		select {
+		case obj := <-order:
+			if obj != i {
+				t.Fatalf("expected waiter %d to be served next, got waiter %d", i, obj)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for waiter %d to be served", i)
+		}
+	}
+}
+
+// TestFairnessNoObjectLossOnTimeout races a waiter's timeout against a
+// concurrent offer and asserts no object is ever lost: after many
+// iterations of borrow-under-contention-then-timeout, with every successful
+// borrower having returned what it got, every object the pool created
+// (created minus destroyed) is back in idleObjects. An object dropped into a
+// waiter's channel that nobody reads would leave idle short of createCount.
+func TestFairnessNoObjectLossOnTimeout(t *testing.T) {
+	factory := &testFactory{}
+	config := NewDefaultPoolConfig()
+	config.MaxTotal = 1
+	config.Fairness = true
+	config.MaxWaitMillis = 1
+	p := NewObjectPool(factory, config)
+
+	held, err := p.BorrowObject()
+	if err != nil {
+		t.Fatalf("initial borrow failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	const attempts = 200
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Most of these race the 1ms timeout against ReturnObject below
+			// and are expected to time out; a few may win the race and
+			// borrow successfully, and must return what they got.
+			obj, err := p.BorrowObject()
+			if err == nil {
+				p.ReturnObject(obj)
+			}
+		}()
+	}
+	// Return the held object partway through the burst so some waiters'
+	// timeouts race directly against offer() delivering it.
+	time.Sleep(time.Millisecond)
+	p.ReturnObject(held)
+	wg.Wait()
+
+	if p.GetNumIdle() != int(p.createCount.Get()) {
+		t.Fatalf("object accounting diverged: idle=%d active=%d createCount=%d",
+			p.GetNumIdle(), p.GetNumActive(), p.createCount.Get())
+	}
+}
+
+// TestFairnessContextCancelNoLoss is like TestFairnessNoObjectLossOnTimeout
+// but gives up via ctx cancellation instead of the configured timeout,
+// exercising the same cancel-vs-offer race through
+// BorrowObjectWithContext/waitFair.
+func TestFairnessContextCancelNoLoss(t *testing.T) {
+	factory := &testFactory{}
+	config := NewDefaultPoolConfig()
+	config.MaxTotal = 1
+	config.Fairness = true
+	p := NewObjectPool(factory, config)
+
+	held, err := p.BorrowObject()
+	if err != nil {
+		t.Fatalf("initial borrow failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	const attempts = 200
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancel()
+			obj, err := p.BorrowObjectWithContext(ctx)
+			if err == nil {
+				p.ReturnObject(obj)
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	p.ReturnObject(held)
+	wg.Wait()
+
+	if p.GetNumIdle() != int(p.createCount.Get()) {
+		t.Fatalf("object accounting diverged: idle=%d active=%d createCount=%d",
+			p.GetNumIdle(), p.GetNumActive(), p.createCount.Get())
+	}
+}
@@ -0,0 +1,472 @@
+package pool
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jolestar/go-commons-pool/collections"
+	"github.com/jolestar/go-commons-pool/concurrent"
+)
+
+// KeyedPooledObjectFactory is the keyed counterpart of PooledObjectFactory.
+// Every lifecycle callback receives the key the object was (or is to be)
+// associated with, so a single factory can know how to build, check and
+// tear down objects for many distinct keys (e.g. one TCP connection per
+// host:port).
+type KeyedPooledObjectFactory interface {
+	MakeObject(key interface{}) (*PooledObject, error)
+	ActivateObject(key interface{}, object *PooledObject) error
+	PassivateObject(key interface{}, object *PooledObject) error
+	ValidateObject(key interface{}, object *PooledObject) bool
+	DestroyObject(key interface{}, object *PooledObject) error
+}
+
+// keyedObjectDeque bundles the idle objects and bookkeeping that belong to a
+// single key.
+type keyedObjectDeque struct {
+	idleObjects *collections.LinkedBlockingDeque
+	allObjects  *collections.SyncIdentityMap
+	createCount concurrent.AtomicInteger
+}
+
+func newKeyedObjectDeque() *keyedObjectDeque {
+	return &keyedObjectDeque{
+		idleObjects: collections.NewDeque(math.MaxInt32),
+		allObjects:  collections.NewSyncMap(),
+	}
+}
+
+// KeyedObjectPool partitions a pool of objects by an arbitrary key, mirroring
+// ObjectPool but applying MaxTotalPerKey alongside a pool-wide MaxTotal. It is
+// useful for pooling resources that are only interchangeable within the same
+// key, such as a connection per shard id.
+type KeyedObjectPool struct {
+	Config  *KeyedObjectPoolConfig
+	factory KeyedPooledObjectFactory
+
+	closed    bool
+	closeLock sync.Mutex
+
+	poolMapLock sync.RWMutex
+	poolMap     map[interface{}]*keyedObjectDeque
+	poolKeys    []interface{}
+	evictCursor int
+
+	createCount concurrent.AtomicInteger
+
+	evictionLock sync.Mutex
+	evictor      *time.Ticker
+}
+
+// NewKeyedObjectPool creates a KeyedObjectPool with the given factory and config.
+func NewKeyedObjectPool(factory KeyedPooledObjectFactory, config *KeyedObjectPoolConfig) *KeyedObjectPool {
+	pool := &KeyedObjectPool{
+		factory: factory,
+		Config:  config,
+		poolMap: make(map[interface{}]*keyedObjectDeque),
+	}
+	return pool
+}
+
+// NewKeyedObjectPoolWithDefaultConfig creates a KeyedObjectPool using NewDefaultKeyedPoolConfig.
+func NewKeyedObjectPoolWithDefaultConfig(factory KeyedPooledObjectFactory) *KeyedObjectPool {
+	return NewKeyedObjectPool(factory, NewDefaultKeyedPoolConfig())
+}
+
+// register returns the deque for key, creating it (and registering the key
+// for round-robin eviction) if this is the first time key has been seen.
+func (this *KeyedObjectPool) register(key interface{}) *keyedObjectDeque {
+	this.poolMapLock.RLock()
+	deque, ok := this.poolMap[key]
+	this.poolMapLock.RUnlock()
+	if ok {
+		return deque
+	}
+
+	this.poolMapLock.Lock()
+	defer this.poolMapLock.Unlock()
+	deque, ok = this.poolMap[key]
+	if ok {
+		return deque
+	}
+	deque = newKeyedObjectDeque()
+	this.poolMap[key] = deque
+	this.poolKeys = append(this.poolKeys, key)
+	return deque
+}
+
+func (this *KeyedObjectPool) IsClosed() bool {
+	this.closeLock.Lock()
+	defer this.closeLock.Unlock()
+	return this.closed
+}
+
+func (this *KeyedObjectPool) create(key interface{}, deque *keyedObjectDeque) *PooledObject {
+	maxTotalPerKey := this.Config.MaxTotalPerKey
+	newKeyCreateCount := deque.createCount.IncrementAndGet()
+	if maxTotalPerKey > -1 && int(newKeyCreateCount) > maxTotalPerKey {
+		deque.createCount.DecrementAndGet()
+		return nil
+	}
+	maxTotal := this.Config.MaxTotal
+	newTotalCreateCount := this.createCount.IncrementAndGet()
+	if maxTotal > -1 && int(newTotalCreateCount) > maxTotal {
+		this.createCount.DecrementAndGet()
+		deque.createCount.DecrementAndGet()
+		return nil
+	}
+
+	p, e := this.factory.MakeObject(key)
+	if e != nil {
+		this.createCount.DecrementAndGet()
+		deque.createCount.DecrementAndGet()
+		return nil
+	}
+	deque.allObjects.Put(p.Object, p)
+	return p
+}
+
+// evictIdleFromAnotherKey destroys one idle object belonging to a key other
+// than excludeKey, freeing a global MaxTotal slot. Without this, a borrower
+// for a key that is itself within MaxTotalPerKey can still be refused by
+// create() because MaxTotal is saturated by other keys - and since a return
+// on a different key never signals excludeKey's own idleObjects deque, that
+// borrower would otherwise block forever under the default
+// MaxWaitMillis=-1 even though capacity was freed elsewhere. It returns
+// false if no other key currently has an idle object to give up.
+func (this *KeyedObjectPool) evictIdleFromAnotherKey(excludeKey interface{}) bool {
+	this.poolMapLock.RLock()
+	keys := make([]interface{}, len(this.poolKeys))
+	copy(keys, this.poolKeys)
+	this.poolMapLock.RUnlock()
+
+	for _, k := range keys {
+		if k == excludeKey {
+			continue
+		}
+		this.poolMapLock.RLock()
+		deque, ok := this.poolMap[k]
+		this.poolMapLock.RUnlock()
+		if !ok {
+			continue
+		}
+		if p, ok := deque.idleObjects.PollFirst().(*PooledObject); ok {
+			this.destroy(k, deque, p)
+			return true
+		}
+	}
+	return false
+}
+
+func (this *KeyedObjectPool) destroy(key interface{}, deque *keyedObjectDeque, toDestroy *PooledObject) {
+	toDestroy.Invalidate()
+	deque.idleObjects.RemoveFirstOccurrence(toDestroy)
+	deque.allObjects.Remove(toDestroy.Object)
+	this.factory.DestroyObject(key, toDestroy)
+	deque.createCount.DecrementAndGet()
+	this.createCount.DecrementAndGet()
+}
+
+// BorrowObject obtains an instance from the sub-pool for key, creating a new
+// one with the factory if the per-key and global MaxTotal allow it.
+func (this *KeyedObjectPool) BorrowObject(key interface{}) (interface{}, error) {
+	if this.IsClosed() {
+		return nil, NewIllegalStatusErr("Pool not open")
+	}
+	deque := this.register(key)
+
+	var p *PooledObject
+	blockWhenExhausted := this.Config.BlockWhenExhausted
+	borrowMaxWaitMillis := this.Config.MaxWaitMillis
+
+	for p == nil {
+		var ok bool
+		p, ok = deque.idleObjects.PollFirst().(*PooledObject)
+		if !ok {
+			p = this.create(key, deque)
+			if p == nil && this.Config.MaxTotal > -1 {
+				maxTotalPerKey := this.Config.MaxTotalPerKey
+				perKeyRoom := maxTotalPerKey < 0 || int(deque.createCount.Get()) < maxTotalPerKey
+				if perKeyRoom && this.evictIdleFromAnotherKey(key) {
+					p = this.create(key, deque)
+				}
+			}
+		}
+		if p == nil && blockWhenExhausted {
+			var obj interface{}
+			var err error
+			if borrowMaxWaitMillis < 0 {
+				obj, err = deque.idleObjects.TakeFirst()
+			} else {
+				obj, err = deque.idleObjects.PollFirstWithTimeout(time.Duration(borrowMaxWaitMillis) * time.Millisecond)
+			}
+			if err != nil {
+				return nil, err
+			}
+			p, ok = obj.(*PooledObject)
+			if !ok {
+				return nil, NewNoSuchElementErr("Timeout waiting for idle object")
+			}
+		}
+		if p == nil {
+			return nil, NewNoSuchElementErr("Pool exhausted for key")
+		}
+		if !p.Allocate() {
+			p = nil
+			continue
+		}
+
+		if e := this.factory.ActivateObject(key, p); e != nil {
+			this.destroy(key, deque, p)
+			p = nil
+			continue
+		}
+		if this.Config.TestOnBorrow && !this.factory.ValidateObject(key, p) {
+			this.destroy(key, deque, p)
+			p = nil
+			continue
+		}
+	}
+	return p.Object, nil
+}
+
+// ReturnObject returns object, which must have been obtained via BorrowObject
+// with the same key, back to its key's idle deque.
+func (this *KeyedObjectPool) ReturnObject(key interface{}, object interface{}) error {
+	this.poolMapLock.RLock()
+	deque, ok := this.poolMap[key]
+	this.poolMapLock.RUnlock()
+	if !ok {
+		return NewIllegalStatusErr("Returned object not currently part of this pool")
+	}
+	p, ok := deque.allObjects.Get(object).(*PooledObject)
+	if !ok {
+		return NewIllegalStatusErr("Returned object not currently part of this pool")
+	}
+
+	if this.Config.TestOnReturn && !this.factory.ValidateObject(key, p) {
+		this.destroy(key, deque, p)
+		return nil
+	}
+
+	if err := this.factory.PassivateObject(key, p); err != nil {
+		this.destroy(key, deque, p)
+		return nil
+	}
+
+	if !p.Deallocate() {
+		return NewIllegalStatusErr("Object has already been returned to this pool or is invalid")
+	}
+
+	maxIdlePerKey := this.Config.MaxIdlePerKey
+	if this.IsClosed() || (maxIdlePerKey > -1 && maxIdlePerKey <= deque.idleObjects.Size()) {
+		this.destroy(key, deque, p)
+	} else if this.Config.Lifo {
+		deque.idleObjects.AddFirst(p)
+	} else {
+		deque.idleObjects.AddLast(p)
+	}
+	return nil
+}
+
+// InvalidateObject removes object from the pool without returning it to the
+// idle deque, typically because it was found to be broken.
+func (this *KeyedObjectPool) InvalidateObject(key interface{}, object interface{}) error {
+	this.poolMapLock.RLock()
+	deque, ok := this.poolMap[key]
+	this.poolMapLock.RUnlock()
+	if !ok {
+		return NewIllegalStatusErr("Invalidated object not currently part of this pool")
+	}
+	p, ok := deque.allObjects.Get(object).(*PooledObject)
+	if !ok {
+		return NewIllegalStatusErr("Invalidated object not currently part of this pool")
+	}
+	this.destroy(key, deque, p)
+	return nil
+}
+
+// AddObject pre-loads one idle instance for key.
+func (this *KeyedObjectPool) AddObject(key interface{}) error {
+	if this.IsClosed() {
+		return NewIllegalStatusErr("Pool not open")
+	}
+	deque := this.register(key)
+	p := this.create(key, deque)
+	if p == nil {
+		return NewIllegalStatusErr("Unable to create object for key")
+	}
+	this.factory.PassivateObject(key, p)
+	if this.Config.Lifo {
+		deque.idleObjects.AddFirst(p)
+	} else {
+		deque.idleObjects.AddLast(p)
+	}
+	return nil
+}
+
+// GetNumActive returns the total number of instances currently borrowed
+// across all keys.
+func (this *KeyedObjectPool) GetNumActive() int {
+	return int(this.createCount.Get()) - this.GetNumIdle()
+}
+
+// GetNumActiveByKey returns the number of instances currently borrowed for key.
+func (this *KeyedObjectPool) GetNumActiveByKey(key interface{}) int {
+	this.poolMapLock.RLock()
+	deque, ok := this.poolMap[key]
+	this.poolMapLock.RUnlock()
+	if !ok {
+		return 0
+	}
+	return int(deque.createCount.Get()) - deque.idleObjects.Size()
+}
+
+// GetNumIdle returns the total number of idle instances across all keys.
+func (this *KeyedObjectPool) GetNumIdle() int {
+	this.poolMapLock.RLock()
+	defer this.poolMapLock.RUnlock()
+	total := 0
+	for _, deque := range this.poolMap {
+		total += deque.idleObjects.Size()
+	}
+	return total
+}
+
+// GetNumIdleByKey returns the number of idle instances for key.
+func (this *KeyedObjectPool) GetNumIdleByKey(key interface{}) int {
+	this.poolMapLock.RLock()
+	deque, ok := this.poolMap[key]
+	this.poolMapLock.RUnlock()
+	if !ok {
+		return 0
+	}
+	return deque.idleObjects.Size()
+}
+
+// Clear destroys every idle object for every key.
+func (this *KeyedObjectPool) Clear() {
+	this.poolMapLock.RLock()
+	keys := make([]interface{}, len(this.poolKeys))
+	copy(keys, this.poolKeys)
+	this.poolMapLock.RUnlock()
+	for _, key := range keys {
+		this.ClearKey(key)
+	}
+}
+
+// ClearKey destroys every idle object belonging to key, without affecting
+// other keys.
+func (this *KeyedObjectPool) ClearKey(key interface{}) {
+	this.poolMapLock.RLock()
+	deque, ok := this.poolMap[key]
+	this.poolMapLock.RUnlock()
+	if !ok {
+		return
+	}
+	p, ok := deque.idleObjects.PollFirst().(*PooledObject)
+	for ok {
+		this.destroy(key, deque, p)
+		p, ok = deque.idleObjects.PollFirst().(*PooledObject)
+	}
+}
+
+// Close closes the pool and destroys every idle object it holds.
+func (this *KeyedObjectPool) Close() {
+	if this.IsClosed() {
+		return
+	}
+	this.closeLock.Lock()
+	defer this.closeLock.Unlock()
+	if this.closed {
+		return
+	}
+	this.closed = true
+	this.poolMapLock.RLock()
+	for _, deque := range this.poolMap {
+		deque.idleObjects.InterruptTakeWaiters()
+	}
+	this.poolMapLock.RUnlock()
+	this.Clear()
+}
+
+// nextEvictionKey returns the next key to examine for the round-robin
+// evictor, visiting every registered key in turn before repeating.
+func (this *KeyedObjectPool) nextEvictionKey() (interface{}, bool) {
+	this.poolMapLock.RLock()
+	defer this.poolMapLock.RUnlock()
+	if len(this.poolKeys) == 0 {
+		return nil, false
+	}
+	if this.evictCursor >= len(this.poolKeys) {
+		this.evictCursor = 0
+	}
+	key := this.poolKeys[this.evictCursor]
+	this.evictCursor++
+	return key, true
+}
+
+// evictOneKey runs a single eviction pass over the next key in the
+// round-robin cursor, so that no single busy key can starve the others of
+// eviction runs.
+func (this *KeyedObjectPool) evictOneKey() {
+	this.evictionLock.Lock()
+	defer this.evictionLock.Unlock()
+
+	key, ok := this.nextEvictionKey()
+	if !ok {
+		return
+	}
+	this.poolMapLock.RLock()
+	deque, ok := this.poolMap[key]
+	this.poolMapLock.RUnlock()
+	if !ok {
+		return
+	}
+
+	evictionConfig := EvictionConfig{
+		IdleEvictTime:     this.Config.MinEvictableIdleTimeMillis,
+		IdleSoftEvictTime: this.Config.SoftMinEvictableIdleTimeMillis,
+		MinIdle:           this.Config.MinIdlePerKey,
+	}
+	evictionPolicy := GetEvictionPolicy(this.Config.EvictionPolicyName)
+	if evictionPolicy == nil {
+		evictionPolicy = GetEvictionPolicy(DEFAULT_EVICTION_POLICY_NAME)
+	}
+
+	underTest, ok := deque.idleObjects.PollFirst().(*PooledObject)
+	if !ok {
+		return
+	}
+	if evictionPolicy.Evict(&evictionConfig, underTest, deque.idleObjects.Size()) {
+		this.destroy(key, deque, underTest)
+	} else if this.Config.Lifo {
+		deque.idleObjects.AddFirst(underTest)
+	} else {
+		deque.idleObjects.AddLast(underTest)
+	}
+}
+
+// StartEvictor starts (or restarts, if Config.TimeBetweenEvictionRunsMillis
+// changed) the background goroutine that visits keys in round-robin order.
+func (this *KeyedObjectPool) StartEvictor() {
+	this.evictionLock.Lock()
+	if this.evictor != nil {
+		this.evictor.Stop()
+		this.evictor = nil
+	}
+	delay := this.Config.TimeBetweenEvictionRunsMillis
+	this.evictionLock.Unlock()
+	if delay > 0 {
+		this.evictionLock.Lock()
+		this.evictor = time.NewTicker(time.Duration(delay) * time.Millisecond)
+		ticker := this.evictor
+		this.evictionLock.Unlock()
+		go func() {
+			for range ticker.C {
+				this.evictOneKey()
+			}
+		}()
+	}
+}
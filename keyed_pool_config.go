@@ -0,0 +1,68 @@
+package pool
+
+import "math"
+
+// KeyedObjectPoolConfig holds the tunables for a KeyedObjectPool. It mirrors
+// ObjectPoolConfig, adding MaxTotalPerKey/MaxIdlePerKey/MinIdlePerKey so each
+// key can be capped independently while MaxTotal still bounds the pool as a
+// whole.
+type KeyedObjectPoolConfig struct {
+	// MaxTotal caps the combined number of instances, across every key, that
+	// can be allocated by this pool at one time. A negative value means no
+	// limit.
+	MaxTotal int
+
+	// MaxTotalPerKey caps the number of instances for a single key. A
+	// negative value means no per-key limit.
+	MaxTotalPerKey int
+
+	// MaxIdlePerKey caps the number of idle instances kept around for a
+	// single key. A negative value means no limit.
+	MaxIdlePerKey int
+
+	// MinIdlePerKey is passed to the eviction policy as its MinIdle, so soft
+	// idle-time eviction keeps at least this many idle instances for a key.
+	// The evictor does not create objects to bring a key up to it.
+	MinIdlePerKey int
+
+	Lifo bool
+
+	BlockWhenExhausted bool
+	MaxWaitMillis      int64
+
+	TestOnCreate  bool
+	TestOnBorrow  bool
+	TestOnReturn  bool
+	TestWhileIdle bool
+
+	TimeBetweenEvictionRunsMillis  int64
+	NumTestsPerEvictionRun         int
+	MinEvictableIdleTimeMillis     int64
+	SoftMinEvictableIdleTimeMillis int64
+	EvictionPolicyName             string
+}
+
+// NewDefaultKeyedPoolConfig returns a KeyedObjectPoolConfig with the same
+// defaults NewDefaultPoolConfig uses for ObjectPool, with MaxTotalPerKey,
+// MaxIdlePerKey and MinIdlePerKey taking ObjectPool's MaxTotal, MaxIdle and
+// MinIdle defaults, and the pool-wide MaxTotal left unlimited (-1).
+func NewDefaultKeyedPoolConfig() *KeyedObjectPoolConfig {
+	return &KeyedObjectPoolConfig{
+		MaxTotal:                       -1,
+		MaxTotalPerKey:                 8,
+		MaxIdlePerKey:                  8,
+		MinIdlePerKey:                  0,
+		Lifo:                           true,
+		BlockWhenExhausted:             true,
+		MaxWaitMillis:                  -1,
+		TestOnCreate:                   false,
+		TestOnBorrow:                   false,
+		TestOnReturn:                   false,
+		TestWhileIdle:                  false,
+		TimeBetweenEvictionRunsMillis:  -1,
+		NumTestsPerEvictionRun:         3,
+		MinEvictableIdleTimeMillis:     1000 * 60 * 30,
+		SoftMinEvictableIdleTimeMillis: math.MaxInt64,
+		EvictionPolicyName:             DEFAULT_EVICTION_POLICY_NAME,
+	}
+}
@@ -0,0 +1,124 @@
+package pool
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// testKeyedFactory is a minimal KeyedPooledObjectFactory that hands out
+// sequentially numbered values per key and never fails or invalidates
+// anything, so tests only have to reason about the pool's own bookkeeping.
+type testKeyedFactory struct {
+	created int64
+}
+
+func (this *testKeyedFactory) MakeObject(key interface{}) (*PooledObject, error) {
+	n := atomic.AddInt64(&this.created, 1)
+	value := fmt.Sprintf("%v-%d", key, n)
+	return NewPooledObject(&value), nil
+}
+
+func (this *testKeyedFactory) ActivateObject(key interface{}, object *PooledObject) error {
+	return nil
+}
+
+func (this *testKeyedFactory) PassivateObject(key interface{}, object *PooledObject) error {
+	return nil
+}
+
+func (this *testKeyedFactory) ValidateObject(key interface{}, object *PooledObject) bool {
+	return true
+}
+
+func (this *testKeyedFactory) DestroyObject(key interface{}, object *PooledObject) error {
+	return nil
+}
+
+// TestKeyedObjectPoolMaxTotalPerKey checks that a single key can never hold
+// more than MaxTotalPerKey instances at once, even though MaxTotal (the
+// pool-wide cap) is left high enough not to interfere.
+func TestKeyedObjectPoolMaxTotalPerKey(t *testing.T) {
+	factory := &testKeyedFactory{}
+	config := NewDefaultKeyedPoolConfig()
+	config.MaxTotal = -1
+	config.MaxTotalPerKey = 2
+	config.BlockWhenExhausted = false
+	p := NewKeyedObjectPool(factory, config)
+
+	if _, err := p.BorrowObject("a"); err != nil {
+		t.Fatalf("expected first borrow for key a to succeed, got %v", err)
+	}
+	if _, err := p.BorrowObject("a"); err != nil {
+		t.Fatalf("expected second borrow for key a to succeed, got %v", err)
+	}
+	if _, err := p.BorrowObject("a"); err == nil {
+		t.Fatal("expected third borrow for key a to fail MaxTotalPerKey")
+	}
+
+	// A different key is unaffected by key "a" being exhausted.
+	if _, err := p.BorrowObject("b"); err != nil {
+		t.Fatalf("expected borrow for key b to succeed, got %v", err)
+	}
+}
+
+// TestKeyedObjectPoolMaxTotalAcrossKeys checks that the pool-wide MaxTotal
+// still caps the combined instance count even when every individual key is
+// well within its own MaxTotalPerKey.
+func TestKeyedObjectPoolMaxTotalAcrossKeys(t *testing.T) {
+	factory := &testKeyedFactory{}
+	config := NewDefaultKeyedPoolConfig()
+	config.MaxTotal = 3
+	config.MaxTotalPerKey = -1
+	config.BlockWhenExhausted = false
+	p := NewKeyedObjectPool(factory, config)
+
+	keys := []string{"a", "b", "c", "d"}
+	borrowed := 0
+	for _, key := range keys {
+		if _, err := p.BorrowObject(key); err == nil {
+			borrowed++
+		}
+	}
+	if borrowed != config.MaxTotal {
+		t.Fatalf("expected exactly MaxTotal=%d successful borrows across keys, got %d", config.MaxTotal, borrowed)
+	}
+	if p.GetNumActive() != config.MaxTotal {
+		t.Fatalf("expected GetNumActive()=%d, got %d", config.MaxTotal, p.GetNumActive())
+	}
+}
+
+// TestKeyedObjectPoolMaxTotalPerKeyConcurrent borrows for the same key from
+// many goroutines at once, so register/create's increment-then-check on
+// deque.createCount is exercised under real contention rather than in
+// sequence.
+func TestKeyedObjectPoolMaxTotalPerKeyConcurrent(t *testing.T) {
+	factory := &testKeyedFactory{}
+	config := NewDefaultKeyedPoolConfig()
+	config.MaxTotal = -1
+	config.MaxTotalPerKey = 5
+	config.BlockWhenExhausted = false
+	p := NewKeyedObjectPool(factory, config)
+
+	const attempts = 50
+	var wg sync.WaitGroup
+	var succeeded int64
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.BorrowObject("k"); err == nil {
+				atomic.AddInt64(&succeeded, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != int64(config.MaxTotalPerKey) {
+		t.Fatalf("expected exactly MaxTotalPerKey=%d successful borrows under contention, got %d", config.MaxTotalPerKey, succeeded)
+	}
+	if p.GetNumActiveByKey("k") != config.MaxTotalPerKey {
+		t.Fatalf("expected GetNumActiveByKey(\"k\")=%d, got %d", config.MaxTotalPerKey, p.GetNumActiveByKey("k"))
+	}
+}
@@ -0,0 +1,79 @@
+package pool
+
+import "math"
+
+// ObjectPoolConfig holds the tunables for an ObjectPool.
+type ObjectPoolConfig struct {
+	// Lifo controls whether idle objects are handed out LIFO (true) or FIFO
+	// (false, round-robin).
+	Lifo bool
+
+	// Fairness controls how waiting borrowers are served once the pool is
+	// exhausted. When false (the default), a waiter is woken via the idle
+	// deque's condition variable and Go's runtime gives no ordering
+	// guarantee between waiters, so an unlucky goroutine can be starved by a
+	// busy pool that keeps creating and returning objects around it. When
+	// true, BorrowObject instead joins an explicit FIFO queue and is handed
+	// the next returned/created object directly, in the order it started
+	// waiting.
+	Fairness bool
+
+	MaxTotal           int
+	MaxIdle            int
+	MinIdle            int
+	BlockWhenExhausted bool
+	MaxWaitMillis      int64
+
+	TestOnCreate  bool
+	TestOnBorrow  bool
+	TestOnReturn  bool
+	TestWhileIdle bool
+
+	TimeBetweenEvictionRunsMillis  int64
+	NumTestsPerEvictionRun         int
+	MinEvictableIdleTimeMillis     int64
+	SoftMinEvictableIdleTimeMillis int64
+	EvictionPolicyName             string
+
+	// AsyncCreate moves factory.MakeObject calls off of the borrower's
+	// goroutine and onto a small pool of background creation workers, so a
+	// slow MakeObject (e.g. a TCP dial) doesn't add its latency directly to
+	// BorrowObject. Borrowers instead wait on the normal idle-object path,
+	// the same way they would if another goroutine had just returned an
+	// object. This applies only when BlockWhenExhausted is true; with
+	// BlockWhenExhausted false, BorrowObject still calls MakeObject on the
+	// borrower's goroutine.
+	AsyncCreate bool
+
+	// CreateConcurrency is the number of background goroutines draining
+	// creation requests when AsyncCreate is true. It also bounds how many
+	// creation requests can be pending at once; a burst of borrowers beyond
+	// that is coalesced rather than queued unbounded. Defaults to 1 if left
+	// at zero.
+	CreateConcurrency int
+}
+
+// NewDefaultPoolConfig returns an ObjectPoolConfig with the same defaults
+// Apache commons-pool2's GenericObjectPoolConfig uses.
+func NewDefaultPoolConfig() *ObjectPoolConfig {
+	return &ObjectPoolConfig{
+		Lifo:                           true,
+		Fairness:                       false,
+		MaxTotal:                       8,
+		MaxIdle:                        8,
+		MinIdle:                        0,
+		BlockWhenExhausted:             true,
+		MaxWaitMillis:                  -1,
+		TestOnCreate:                   false,
+		TestOnBorrow:                   false,
+		TestOnReturn:                   false,
+		TestWhileIdle:                  false,
+		TimeBetweenEvictionRunsMillis:  -1,
+		NumTestsPerEvictionRun:         3,
+		MinEvictableIdleTimeMillis:     1000 * 60 * 30,
+		SoftMinEvictableIdleTimeMillis: math.MaxInt64,
+		EvictionPolicyName:             DEFAULT_EVICTION_POLICY_NAME,
+		AsyncCreate:                    false,
+		CreateConcurrency:              1,
+	}
+}
@@ -1,6 +1,7 @@
 package pool
 
 import (
+	"context"
 	"errors"
 	"github.com/jolestar/go-commons-pool/collections"
 	"github.com/jolestar/go-commons-pool/concurrent"
@@ -48,6 +49,10 @@ type ObjectPool struct {
 	destroyedByBorrowValidationCount concurrent.AtomicInteger
 	evictor                          *time.Ticker
 	evictionIterator                 collections.Iterator
+	metricsSink                      MetricsSink
+	stats                            *poolStats
+	fairWaiters                      *fifoWaiterQueue
+	asyncCreator                     *asyncCreator
 }
 
 func NewObjectPool(factory PooledObjectFactory, config *ObjectPoolConfig) *ObjectPool {
@@ -56,11 +61,33 @@ func NewObjectPool(factory PooledObjectFactory, config *ObjectPoolConfig) *Objec
 		allObjects:              collections.NewSyncMap(),
 		createCount:             concurrent.AtomicInteger(0),
 		destroyedByEvictorCount: concurrent.AtomicInteger(0),
-		destroyedCount:          concurrent.AtomicInteger(0)}
+		destroyedCount:          concurrent.AtomicInteger(0),
+		metricsSink:             NoopMetricsSink{},
+		stats:                   newPoolStats(),
+		fairWaiters:             newFifoWaiterQueue()}
+	if config.AsyncCreate {
+		pool.asyncCreator = newAsyncCreator(&pool, config.CreateConcurrency)
+	}
 	pool.StartEvictor()
 	return &pool
 }
 
+// SetMetricsSink wires sink up to receive OnBorrow/OnReturn/OnCreate/
+// OnDestroy/OnEvict events as this pool operates. Passing nil restores the
+// no-op sink.
+func (this *ObjectPool) SetMetricsSink(sink MetricsSink) {
+	if sink == nil {
+		sink = NoopMetricsSink{}
+	}
+	this.metricsSink = sink
+}
+
+// GetStats returns a snapshot of this pool's statistics: counts plus wait/
+// active/idle time histograms.
+func (this *ObjectPool) GetStats() PoolStats {
+	return this.stats.snapshot()
+}
+
 func NewObjectPoolWithDefaultConfig(factory PooledObjectFactory) *ObjectPool {
 	return NewObjectPool(factory, NewDefaultPoolConfig())
 }
@@ -82,11 +109,7 @@ func (this *ObjectPool) AddObject() error {
 func (this *ObjectPool) addIdleObject(p *PooledObject) {
 	if p != nil {
 		this.factory.PassivateObject(p)
-		if this.Config.Lifo {
-			this.idleObjects.AddFirst(p)
-		} else {
-			this.idleObjects.AddLast(p)
-		}
+		this.offerIdle(p)
 	}
 }
 
@@ -101,7 +124,16 @@ func (this *ObjectPool) addIdleObject(p *PooledObject) {
 // By contract, clients must return the borrowed instance
 // using ReturnObject, InvalidateObject
 func (this *ObjectPool) BorrowObject() (interface{}, error) {
-	return this.borrowObject(this.Config.MaxWaitMillis)
+	return this.borrowObject(context.Background(), this.Config.MaxWaitMillis)
+}
+
+// BorrowObjectWithContext is equivalent to BorrowObject, except the wait for
+// an idle object unblocks as soon as ctx is done, returning ctx.Err() instead
+// of waiting out the full MaxWaitMillis. This lets callers tie a borrow to a
+// request deadline or a caller-initiated cancellation (e.g. an HTTP handler
+// whose client disconnected).
+func (this *ObjectPool) BorrowObjectWithContext(ctx context.Context) (interface{}, error) {
+	return this.borrowObject(ctx, this.Config.MaxWaitMillis)
 }
 
 //Return the number of instances currently idle in this pool. This may be
@@ -171,6 +203,8 @@ func (this *ObjectPool) create() *PooledObject {
 	//		p.setLogAbandoned(true);
 	//	}
 	this.allObjects.Put(p.Object, p)
+	this.stats.recordCreate()
+	this.metricsSink.OnCreate(p)
 	return p
 }
 
@@ -190,19 +224,20 @@ func (this *ObjectPool) doDestroy(toDestroy *PooledObject, inLock bool) {
 	this.factory.DestroyObject(toDestroy)
 	this.destroyedCount.IncrementAndGet()
 	this.createCount.DecrementAndGet()
+	this.metricsSink.OnDestroy(toDestroy)
 }
 
 func (this *ObjectPool) updateStatsBorrow(object *PooledObject, timeMillis int64) {
-	//TODO
+	this.stats.recordBorrow(timeMillis)
+	this.metricsSink.OnBorrow(object, time.Duration(timeMillis)*time.Millisecond)
 }
 
 func (this *ObjectPool) updateStatsReturn(activeTime int64) {
-	//TODO
-	//returnedCount.incrementAndGet();
-	//activeTimes.add(activeTime);
+	this.stats.recordReturn(activeTime)
+	this.metricsSink.OnReturn(time.Duration(activeTime) * time.Millisecond)
 }
 
-func (this *ObjectPool) borrowObject(borrowMaxWaitMillis int64) (interface{}, error) {
+func (this *ObjectPool) borrowObject(ctx context.Context, borrowMaxWaitMillis int64) (interface{}, error) {
 	if this.IsClosed() {
 		return nil, NewIllegalStatusErr("Pool not open")
 	}
@@ -221,27 +256,39 @@ func (this *ObjectPool) borrowObject(borrowMaxWaitMillis int64) (interface{}, er
 
 	var create bool
 	waitTime := currentTimeMillis()
+	var idleSince int64
 	var ok bool
 	for p == nil {
 		create = false
 		if blockWhenExhausted {
 			p, ok = this.idleObjects.PollFirst().(*PooledObject)
 			if !ok {
-				p = this.create()
-				if p != nil {
-					create = true
-					ok = true
+				if this.Config.AsyncCreate {
+					this.asyncCreator.requestCreate()
+				} else {
+					p = this.create()
+					if p != nil {
+						create = true
+						ok = true
+					}
 				}
 			}
 			if p == nil {
-				if borrowMaxWaitMillis < 0 {
-					obj, err := this.idleObjects.TakeFirst()
+				if this.Config.Fairness {
+					fairObj, err := this.waitFair(ctx, borrowMaxWaitMillis)
+					if err != nil {
+						return nil, err
+					}
+					p = fairObj
+					ok = p != nil
+				} else if borrowMaxWaitMillis < 0 {
+					obj, err := this.takeFirstWithContext(ctx)
 					if err != nil {
 						return nil, err
 					}
 					p, ok = obj.(*PooledObject)
 				} else {
-					obj, err := this.idleObjects.PollFirstWithTimeout(time.Duration(borrowMaxWaitMillis) * time.Millisecond)
+					obj, err := this.pollFirstWithContext(ctx, time.Duration(borrowMaxWaitMillis)*time.Millisecond)
 					if err != nil {
 						return nil, err
 					}
@@ -252,6 +299,9 @@ func (this *ObjectPool) borrowObject(borrowMaxWaitMillis int64) (interface{}, er
 			if !ok {
 				return nil, NewNoSuchElementErr("Timeout waiting for idle object")
 			}
+			if !create {
+				idleSince = p.GetLastUsedTime()
+			}
 			if !p.Allocate() {
 				p = nil
 			}
@@ -266,6 +316,9 @@ func (this *ObjectPool) borrowObject(borrowMaxWaitMillis int64) (interface{}, er
 			if p == nil {
 				return nil, NewNoSuchElementErr("Pool exhausted")
 			}
+			if !create {
+				idleSince = p.GetLastUsedTime()
+			}
 			if !p.Allocate() {
 				p = nil
 			}
@@ -295,6 +348,9 @@ func (this *ObjectPool) borrowObject(borrowMaxWaitMillis int64) (interface{}, er
 	}
 
 	this.updateStatsBorrow(p, currentTimeMillis()-waitTime)
+	if !create {
+		this.stats.recordIdle(p.LastBorrowTime - idleSince)
+	}
 	return p.Object, nil
 }
 
@@ -307,17 +363,19 @@ func (this *ObjectPool) ensureIdle(idleCount int, always bool) {
 		return
 	}
 
-	for this.idleObjects.Size() < idleCount {
-		p := this.create()
-		if p == nil {
-			// Can't create objects, no reason to think another call to
-			// create will work. Give up.
-			break
+	if this.Config.AsyncCreate {
+		for i := this.idleObjects.Size(); i < idleCount; i++ {
+			this.asyncCreator.requestCreate()
 		}
-		if this.Config.Lifo {
-			this.idleObjects.AddFirst(p)
-		} else {
-			this.idleObjects.AddLast(p)
+	} else {
+		for this.idleObjects.Size() < idleCount {
+			p := this.create()
+			if p == nil {
+				// Can't create objects, no reason to think another call to
+				// create will work. Give up.
+				break
+			}
+			this.offerIdle(p)
 		}
 	}
 	if this.IsClosed() {
@@ -392,11 +450,7 @@ func (this *ObjectPool) ReturnObject(object interface{}) error {
 	if this.IsClosed() || maxIdleSave > -1 && maxIdleSave <= this.idleObjects.Size() {
 		this.destroy(p)
 	} else {
-		if this.Config.Lifo {
-			this.idleObjects.AddFirst(p)
-		} else {
-			this.idleObjects.AddLast(p)
-		}
+		this.offerIdle(p)
 		if this.IsClosed() {
 			// Pool closed while object was being added to idle objects.
 			// Make sure the returned object is destroyed rather than left
@@ -446,6 +500,33 @@ func (this *ObjectPool) InvalidateObject(object interface{}) error {
 	return nil
 }
 
+// HijackObject removes object, which must currently be borrowed from this
+// pool, from the pool's own tracking (allObjects, createCount) without
+// calling the factory's DestroyObject. Ownership of object transfers fully
+// to the caller, who is now responsible for it; the pool will neither hand
+// it out again nor clean it up. This is useful when upgrading a pooled
+// connection into a long-lived resource (e.g. a Redis pub/sub subscriber or
+// a WebSocket) that must not be returned to the pool but would be wasteful
+// to destroy outright.
+func (this *ObjectPool) HijackObject(object interface{}) error {
+	p, ok := this.allObjects.Get(object).(*PooledObject)
+	if !ok {
+		return NewIllegalStatusErr("Hijacked object not currently part of this pool")
+	}
+	p.lock.Lock()
+	if p.state != ALLOCATED {
+		p.lock.Unlock()
+		return NewIllegalStatusErr("Object is not currently borrowed from this pool")
+	}
+	p.hijack()
+	p.lock.Unlock()
+
+	this.allObjects.Remove(object)
+	this.createCount.DecrementAndGet()
+	this.ensureIdle(1, false)
+	return nil
+}
+
 //Close this pool, and free any resources associated with it.
 func (this *ObjectPool) Close() {
 	if this.IsClosed() {
@@ -467,6 +548,10 @@ func (this *ObjectPool) Close() {
 
 	// Release any threads that were waiting for an object
 	this.idleObjects.InterruptTakeWaiters()
+
+	if this.asyncCreator != nil {
+		this.asyncCreator.Stop()
+	}
 }
 
 //if ObjectPool.Config.TimeBetweenEvictionRunsMillis change, should call this method to let it to take effect.
@@ -585,6 +670,7 @@ func (this *ObjectPool) evict() {
 		if evict {
 			this.destroy(underTest)
 			this.destroyedByEvictorCount.IncrementAndGet()
+			this.metricsSink.OnEvict(underTest)
 		} else {
 			var active bool = false
 			if testWhileIdle {
@@ -0,0 +1,180 @@
+package pool
+
+import (
+	"sync"
+
+	"github.com/jolestar/go-commons-pool/collections"
+)
+
+// PooledObjectState is the lifecycle state of a PooledObject.
+type PooledObjectState int
+
+const (
+	IDLE PooledObjectState = iota
+	ALLOCATED
+	EVICTION
+	VALIDATION
+	INVALID
+	ABANDONED
+	RETURNING
+	// HIJACKED marks an object that has been removed from pool tracking via
+	// PooledObject.Hijack/ObjectPool.HijackObject. It is a terminal state,
+	// just like INVALID, except the factory's DestroyObject is never called
+	// for it.
+	HIJACKED
+)
+
+// PooledObject wraps a value managed by an ObjectPool, tracking the state
+// machine (idle/allocated/under eviction test/invalid/...), timestamps and
+// usage count the pool needs to make borrow/return/eviction decisions
+// without the factory needing to know anything about pooling.
+type PooledObject struct {
+	Object interface{}
+
+	// CreationTime is the Unix millisecond timestamp this object was
+	// created at.
+	CreationTime int64
+
+	// LastBorrowTime is the Unix millisecond timestamp of the most recent
+	// successful Allocate.
+	LastBorrowTime int64
+
+	// UseCount is incremented every time this object is successfully
+	// borrowed, so callers can implement policies like "retire this
+	// connection after N uses".
+	UseCount int64
+
+	lastReturnTime int64
+	state          PooledObjectState
+	lock           sync.Mutex
+}
+
+// NewPooledObject wraps object for tracking by an ObjectPool.
+func NewPooledObject(object interface{}) *PooledObject {
+	now := currentTimeMillis()
+	return &PooledObject{
+		Object:         object,
+		CreationTime:   now,
+		LastBorrowTime: now,
+		lastReturnTime: now,
+		state:          IDLE,
+	}
+}
+
+// GetLastUsedTime returns the Unix millisecond timestamp this object was
+// last borrowed or returned, whichever is more recent.
+func (this *PooledObject) GetLastUsedTime() int64 {
+	if this.lastReturnTime > this.LastBorrowTime {
+		return this.lastReturnTime
+	}
+	return this.LastBorrowTime
+}
+
+// GetActiveTimeMillis returns how long this object has been allocated for,
+// in milliseconds, measured from its most recent Allocate.
+func (this *PooledObject) GetActiveTimeMillis() int64 {
+	elapsed := currentTimeMillis() - this.LastBorrowTime
+	if elapsed < 0 {
+		return 0
+	}
+	return elapsed
+}
+
+// Allocate transitions this object from IDLE (or EVICTION, if an eviction
+// test raced with a borrow) to ALLOCATED, recording LastBorrowTime and
+// incrementing UseCount. It returns false if the object was not in a state
+// that could be allocated.
+func (this *PooledObject) Allocate() bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.state != IDLE && this.state != EVICTION {
+		return false
+	}
+	this.state = ALLOCATED
+	this.LastBorrowTime = currentTimeMillis()
+	this.UseCount++
+	return true
+}
+
+// Deallocate transitions this object from ALLOCATED or RETURNING back to
+// IDLE, recording the return time. It returns false if the object was not
+// currently allocated.
+func (this *PooledObject) Deallocate() bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.state != ALLOCATED && this.state != RETURNING {
+		return false
+	}
+	this.state = IDLE
+	this.lastReturnTime = currentTimeMillis()
+	return true
+}
+
+// markReturning marks this object as in the process of being returned, so
+// the abandoned-object detector does not race with ReturnObject. Callers
+// must already hold this.lock.
+func (this *PooledObject) markReturning() {
+	this.state = RETURNING
+}
+
+// markAbandoned marks this object as abandoned by its borrower. Callers must
+// already hold this.lock.
+func (this *PooledObject) markAbandoned() {
+	this.state = ABANDONED
+}
+
+// invalidate marks this object INVALID. Callers must already hold this.lock.
+func (this *PooledObject) invalidate() {
+	this.state = INVALID
+}
+
+// Invalidate marks this object INVALID, acquiring the lock itself.
+func (this *PooledObject) Invalidate() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.invalidate()
+}
+
+// hijack marks this object HIJACKED. Callers must already hold this.lock.
+func (this *PooledObject) hijack() {
+	this.state = HIJACKED
+}
+
+// Hijack removes this object from pool-managed lifecycle tracking without
+// invoking the factory's DestroyObject, transferring full ownership back to
+// whoever currently holds it. Prefer ObjectPool.HijackObject, which also
+// removes the object from the pool's own bookkeeping; this method only
+// updates the object's own state.
+func (this *PooledObject) Hijack() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.hijack()
+}
+
+// StartEvictionTest transitions this object from IDLE to EVICTION so the
+// evictor can run validation on it without a concurrent borrow succeeding.
+// It returns false if the object was not IDLE, typically because it was
+// borrowed by another goroutine first.
+func (this *PooledObject) StartEvictionTest() bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.state != IDLE {
+		return false
+	}
+	this.state = EVICTION
+	return true
+}
+
+// EndEvictionTest transitions this object from EVICTION back to IDLE once
+// the evictor is done with it. deque is unused today but accepted, as in
+// Apache commons-pool2, to leave room for an EVICTION_RETURN_TO_HEAD state
+// that re-inserts at the front rather than relying on the caller.
+func (this *PooledObject) EndEvictionTest(deque *collections.LinkedBlockingDeque) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.state == EVICTION {
+		this.state = IDLE
+		return true
+	}
+	return false
+}
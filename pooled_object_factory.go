@@ -0,0 +1,13 @@
+package pool
+
+// PooledObjectFactory creates, validates and destroys the objects an
+// ObjectPool manages. MakeObject must return the value wrapped in a
+// PooledObject via NewPooledObject; the other callbacks receive that same
+// PooledObject back so they can reach the wrapped value via its Object field.
+type PooledObjectFactory interface {
+	MakeObject() (*PooledObject, error)
+	ActivateObject(object *PooledObject) error
+	PassivateObject(object *PooledObject) error
+	ValidateObject(object *PooledObject) bool
+	DestroyObject(object *PooledObject) error
+}
@@ -0,0 +1,171 @@
+// Package poolutils provides composable decorators over *pool.ObjectPool,
+// analogous to Apache commons-pool2's PoolUtils, for patterns that are
+// otherwise reimplemented ad hoc by every user of this module: shrinking a
+// pool during quiet periods, auto-invalidating objects that are checked out
+// too long, and keeping a pool topped up on a schedule independent of its
+// own eviction timer.
+package poolutils
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	pool "github.com/jolestar/go-commons-pool"
+)
+
+// erosionDecay is how much an ErodingPool's erosion factor relaxes back
+// towards 1.0 on every successful borrow, so a pool that goes quiet and then
+// gets busy again stops shrinking without waiting for the factor to be reset
+// externally.
+const erosionDecay = 0.9
+
+// ErodingPool wraps an ObjectPool so that, when the pool has been
+// under-utilized, returning an object destroys it instead of idling it -
+// shrinking the pool during quiet periods without relying on
+// TimeBetweenEvictionRunsMillis. The erosion factor starts at 1.0 (no
+// erosion pressure) and grows by one every time ReturnObject is called
+// while more than factor objects are already idle; each return then
+// destroys with probability 1-1/factor instead of idling, so a pool that
+// isn't over-idle (factor == 1) never erodes, and the longer it stays
+// over-idle the more aggressively it does.
+type ErodingPool struct {
+	pool *pool.ObjectPool
+
+	lock   sync.Mutex
+	factor float64
+}
+
+// NewErodingPool wraps p with erosion behavior.
+func NewErodingPool(p *pool.ObjectPool) *ErodingPool {
+	return &ErodingPool{pool: p, factor: 1.0}
+}
+
+// BorrowObject delegates to the wrapped pool, relaxing the erosion factor
+// slightly so an ErodingPool that goes back to being busy stops shrinking.
+func (this *ErodingPool) BorrowObject() (interface{}, error) {
+	object, err := this.pool.BorrowObject()
+	if err == nil {
+		this.lock.Lock()
+		if this.factor > 1.0 {
+			this.factor *= erosionDecay
+			if this.factor < 1.0 {
+				this.factor = 1.0
+			}
+		}
+		this.lock.Unlock()
+	}
+	return object, err
+}
+
+// ReturnObject returns object to the wrapped pool, or destroys it instead if
+// the erosion check decides the pool is currently over-idle.
+func (this *ErodingPool) ReturnObject(object interface{}) error {
+	this.lock.Lock()
+	idle := this.pool.GetNumIdle()
+	if float64(idle) > this.factor {
+		this.factor++
+	}
+	factor := this.factor
+	this.lock.Unlock()
+
+	// factor == 1 means the pool isn't over-idle, so it must never erode;
+	// 1-1/factor is 0 there and grows towards 1 as factor climbs.
+	destroy := factor > 1.0 && rand.Float64() < 1-1/factor
+
+	if destroy {
+		return this.pool.InvalidateObject(object)
+	}
+	return this.pool.ReturnObject(object)
+}
+
+// InvalidateObject delegates to the wrapped pool.
+func (this *ErodingPool) InvalidateObject(object interface{}) error {
+	return this.pool.InvalidateObject(object)
+}
+
+// CheckedOutPool wraps an ObjectPool, starting a timer on every borrowed
+// object and auto-invalidating it if it is not returned within timeout. This
+// is independent of pool.AbandonedConfig, which only runs its checks
+// opportunistically from BorrowObject/the evictor; CheckedOutPool reacts as
+// soon as the timer fires.
+type CheckedOutPool struct {
+	pool    *pool.ObjectPool
+	timeout time.Duration
+
+	lock   sync.Mutex
+	timers map[interface{}]*time.Timer
+}
+
+// NewCheckedOutPool wraps p, invalidating any object not returned within
+// timeout of being borrowed.
+func NewCheckedOutPool(p *pool.ObjectPool, timeout time.Duration) *CheckedOutPool {
+	return &CheckedOutPool{
+		pool:    p,
+		timeout: timeout,
+		timers:  make(map[interface{}]*time.Timer),
+	}
+}
+
+// BorrowObject borrows from the wrapped pool and starts its checkout timer.
+func (this *CheckedOutPool) BorrowObject() (interface{}, error) {
+	object, err := this.pool.BorrowObject()
+	if err != nil {
+		return nil, err
+	}
+	this.lock.Lock()
+	this.timers[object] = time.AfterFunc(this.timeout, func() {
+		this.clearTimer(object)
+		this.pool.InvalidateObject(object)
+	})
+	this.lock.Unlock()
+	return object, nil
+}
+
+// ReturnObject cancels object's checkout timer and returns it to the
+// wrapped pool.
+func (this *CheckedOutPool) ReturnObject(object interface{}) error {
+	this.clearTimer(object)
+	return this.pool.ReturnObject(object)
+}
+
+// InvalidateObject cancels object's checkout timer and invalidates it on
+// the wrapped pool.
+func (this *CheckedOutPool) InvalidateObject(object interface{}) error {
+	this.clearTimer(object)
+	return this.pool.InvalidateObject(object)
+}
+
+func (this *CheckedOutPool) clearTimer(object interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if t, ok := this.timers[object]; ok {
+		t.Stop()
+		delete(this.timers, object)
+	}
+}
+
+// PrefillTask starts a goroutine that, every interval, tops p up to minIdle
+// idle objects, independent of Config.TimeBetweenEvictionRunsMillis. It
+// returns a cancel function that stops the goroutine; callers should call it
+// when p is closed.
+func PrefillTask(p *pool.ObjectPool, minIdle int, interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				for p.GetNumIdle() < minIdle {
+					if err := p.AddObject(); err != nil {
+						break
+					}
+				}
+			case <-stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
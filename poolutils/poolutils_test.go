@@ -0,0 +1,161 @@
+package poolutils
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pool "github.com/jolestar/go-commons-pool"
+)
+
+// countingFactory hands out sequentially numbered values (wrapped in a
+// pointer, since pool's object-identity bookkeeping requires one) and
+// tracks how many it has destroyed, so tests can observe erosion/eviction
+// decisions without caring which particular object was affected.
+type countingFactory struct {
+	created   int64
+	destroyed int64
+}
+
+func (this *countingFactory) MakeObject() (*pool.PooledObject, error) {
+	n := atomic.AddInt64(&this.created, 1)
+	value := fmt.Sprintf("obj-%d", n)
+	return pool.NewPooledObject(&value), nil
+}
+
+func (this *countingFactory) ActivateObject(object *pool.PooledObject) error  { return nil }
+func (this *countingFactory) PassivateObject(object *pool.PooledObject) error { return nil }
+func (this *countingFactory) ValidateObject(object *pool.PooledObject) bool   { return true }
+func (this *countingFactory) DestroyObject(object *pool.PooledObject) error {
+	atomic.AddInt64(&this.destroyed, 1)
+	return nil
+}
+
+// TestErodingPoolNeverErodesWhenNotOverIdle checks the factor==1 guard: a
+// pool that is never more than factor objects idle must never destroy a
+// returned object instead of idling it.
+func TestErodingPoolNeverErodesWhenNotOverIdle(t *testing.T) {
+	factory := &countingFactory{}
+	config := pool.NewDefaultPoolConfig()
+	config.MaxTotal = 1
+	p := pool.NewObjectPool(factory, config)
+	ep := NewErodingPool(p)
+
+	for i := 0; i < 20; i++ {
+		object, err := ep.BorrowObject()
+		if err != nil {
+			t.Fatalf("BorrowObject failed: %v", err)
+		}
+		if err := ep.ReturnObject(object); err != nil {
+			t.Fatalf("ReturnObject failed: %v", err)
+		}
+	}
+	if destroyed := atomic.LoadInt64(&factory.destroyed); destroyed != 0 {
+		t.Fatalf("expected no erosion while factor stays at 1, destroyed %d objects", destroyed)
+	}
+}
+
+// TestErodingPoolErodesWhenOverIdle forces the erosion factor up directly
+// (the same effect sustained over-idle returns would have) and checks that
+// ReturnObject then destroys instead of idling with high probability,
+// rather than the pre-fix inverted model where factor==1 destroyed
+// everything and a high factor destroyed nothing.
+func TestErodingPoolErodesWhenOverIdle(t *testing.T) {
+	factory := &countingFactory{}
+	config := pool.NewDefaultPoolConfig()
+	config.MaxTotal = 50
+	p := pool.NewObjectPool(factory, config)
+	ep := NewErodingPool(p)
+	ep.factor = 20.0
+
+	const attempts = 100
+	for i := 0; i < attempts; i++ {
+		object, err := ep.BorrowObject()
+		if err != nil {
+			t.Fatalf("BorrowObject failed: %v", err)
+		}
+		if err := ep.ReturnObject(object); err != nil {
+			t.Fatalf("ReturnObject failed: %v", err)
+		}
+	}
+	destroyed := atomic.LoadInt64(&factory.destroyed)
+	if destroyed == 0 {
+		t.Fatal("expected a high erosion factor to destroy at least some returned objects")
+	}
+}
+
+// TestCheckedOutPoolInvalidatesAfterTimeout checks that an object not
+// returned within the configured timeout is automatically invalidated.
+func TestCheckedOutPoolInvalidatesAfterTimeout(t *testing.T) {
+	factory := &countingFactory{}
+	config := pool.NewDefaultPoolConfig()
+	config.MaxTotal = 1
+	p := pool.NewObjectPool(factory, config)
+	cp := NewCheckedOutPool(p, 20*time.Millisecond)
+
+	if _, err := cp.BorrowObject(); err != nil {
+		t.Fatalf("BorrowObject failed: %v", err)
+	}
+
+	deadline := time.After(time.Second)
+	for atomic.LoadInt64(&factory.destroyed) == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("checkout timer never invalidated the borrowed object")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+// TestCheckedOutPoolReturnCancelsTimer checks that returning an object
+// before its timeout clears the timer, so it is not later invalidated out
+// from under a second borrower.
+func TestCheckedOutPoolReturnCancelsTimer(t *testing.T) {
+	factory := &countingFactory{}
+	config := pool.NewDefaultPoolConfig()
+	config.MaxTotal = 1
+	p := pool.NewObjectPool(factory, config)
+	cp := NewCheckedOutPool(p, 20*time.Millisecond)
+
+	object, err := cp.BorrowObject()
+	if err != nil {
+		t.Fatalf("BorrowObject failed: %v", err)
+	}
+	if err := cp.ReturnObject(object); err != nil {
+		t.Fatalf("ReturnObject failed: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if destroyed := atomic.LoadInt64(&factory.destroyed); destroyed != 0 {
+		t.Fatalf("expected the timely return to cancel the checkout timer, but %d objects were destroyed", destroyed)
+	}
+}
+
+// TestPrefillTaskToppsUpIdle checks that PrefillTask creates objects on its
+// own schedule until minIdle idle objects are available, and that the
+// returned cancel function stops further creation.
+func TestPrefillTaskToppsUpIdle(t *testing.T) {
+	factory := &countingFactory{}
+	config := pool.NewDefaultPoolConfig()
+	config.MaxTotal = -1
+	p := pool.NewObjectPool(factory, config)
+
+	stop := PrefillTask(p, 3, 10*time.Millisecond)
+
+	deadline := time.After(time.Second)
+	for p.GetNumIdle() < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("PrefillTask never reached minIdle=3, idle=%d", p.GetNumIdle())
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	stop()
+	createdAtStop := atomic.LoadInt64(&factory.created)
+	time.Sleep(50 * time.Millisecond)
+	if created := atomic.LoadInt64(&factory.created); created != createdAtStop {
+		t.Fatalf("expected no further creation after stop, created grew from %d to %d", createdAtStop, created)
+	}
+}
@@ -0,0 +1,236 @@
+package pool
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MetricsSink receives lifecycle events as an ObjectPool operates, so callers
+// can forward them to whatever metrics backend they use (Prometheus,
+// StatsD, ...) without this package taking a dependency on any of them.
+//
+// A minimal Prometheus adapter might look like:
+//
+//	type prometheusSink struct{ borrowed, created prometheus.Counter; wait prometheus.Histogram }
+//	func (s *prometheusSink) OnBorrow(o *pool.PooledObject, wait time.Duration) {
+//		s.borrowed.Inc()
+//		s.wait.Observe(wait.Seconds())
+//	}
+//	// ... OnReturn/OnCreate/OnDestroy/OnEvict similarly, then pool.SetMetricsSink(&prometheusSink{...})
+type MetricsSink interface {
+	OnBorrow(object *PooledObject, waitTime time.Duration)
+	OnReturn(activeTime time.Duration)
+	OnCreate(object *PooledObject)
+	OnDestroy(object *PooledObject)
+	OnEvict(object *PooledObject)
+}
+
+// NoopMetricsSink is the default MetricsSink installed on every ObjectPool;
+// it discards every event.
+type NoopMetricsSink struct{}
+
+func (NoopMetricsSink) OnBorrow(object *PooledObject, waitTime time.Duration) {}
+func (NoopMetricsSink) OnReturn(activeTime time.Duration)                     {}
+func (NoopMetricsSink) OnCreate(object *PooledObject)                         {}
+func (NoopMetricsSink) OnDestroy(object *PooledObject)                        {}
+func (NoopMetricsSink) OnEvict(object *PooledObject)                          {}
+
+// PoolStats is a point-in-time snapshot of an ObjectPool's statistics.
+type PoolStats struct {
+	BorrowedCount       int64
+	ReturnedCount       int64
+	CreatedCount        int64
+	MaxBorrowWaitMillis int64
+
+	WaitTimeP50Millis   int64
+	WaitTimeP95Millis   int64
+	WaitTimeP99Millis   int64
+	ActiveTimeP50Millis int64
+	ActiveTimeP95Millis int64
+	ActiveTimeP99Millis int64
+	IdleTimeP50Millis   int64
+	IdleTimeP95Millis   int64
+	IdleTimeP99Millis   int64
+}
+
+// poolStats is the mutable, internal counterpart of PoolStats.
+type poolStats struct {
+	lock sync.Mutex
+
+	borrowedCount int64
+	returnedCount int64
+	createdCount  int64
+	maxBorrowWait int64
+
+	waitTimes   *quantileEstimator
+	activeTimes *quantileEstimator
+	idleTimes   *quantileEstimator
+}
+
+func newPoolStats() *poolStats {
+	return &poolStats{
+		waitTimes:   newQuantileEstimator(),
+		activeTimes: newQuantileEstimator(),
+		idleTimes:   newQuantileEstimator(),
+	}
+}
+
+func (this *poolStats) recordBorrow(waitMillis int64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.borrowedCount++
+	if waitMillis > this.maxBorrowWait {
+		this.maxBorrowWait = waitMillis
+	}
+	this.waitTimes.Add(float64(waitMillis))
+}
+
+func (this *poolStats) recordReturn(activeMillis int64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.returnedCount++
+	this.activeTimes.Add(float64(activeMillis))
+}
+
+// recordIdle records how long an object sat idle before this borrow claimed
+// it. It is only meaningful for objects that came from idleObjects rather
+// than being freshly created, so callers must skip it on the create path.
+func (this *poolStats) recordIdle(idleMillis int64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.idleTimes.Add(float64(idleMillis))
+}
+
+func (this *poolStats) recordCreate() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.createdCount++
+}
+
+func (this *poolStats) snapshot() PoolStats {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return PoolStats{
+		BorrowedCount:       this.borrowedCount,
+		ReturnedCount:       this.returnedCount,
+		CreatedCount:        this.createdCount,
+		MaxBorrowWaitMillis: this.maxBorrowWait,
+		WaitTimeP50Millis:   int64(this.waitTimes.Quantile(0.50)),
+		WaitTimeP95Millis:   int64(this.waitTimes.Quantile(0.95)),
+		WaitTimeP99Millis:   int64(this.waitTimes.Quantile(0.99)),
+		ActiveTimeP50Millis: int64(this.activeTimes.Quantile(0.50)),
+		ActiveTimeP95Millis: int64(this.activeTimes.Quantile(0.95)),
+		ActiveTimeP99Millis: int64(this.activeTimes.Quantile(0.99)),
+		IdleTimeP50Millis:   int64(this.idleTimes.Quantile(0.50)),
+		IdleTimeP95Millis:   int64(this.idleTimes.Quantile(0.95)),
+		IdleTimeP99Millis:   int64(this.idleTimes.Quantile(0.99)),
+	}
+}
+
+// quantileReservoirSize bounds the number of samples quantileEstimator keeps,
+// trading a small amount of accuracy for a fixed memory footprint.
+const quantileReservoirSize = 1024
+
+// quantileDecayAlpha controls how strongly quantileEstimator favors recent
+// samples over old ones; higher values decay faster. 0.015 matches
+// Dropwizard Metrics' ExponentiallyDecayingReservoir default, which biases
+// noticeably towards the last few minutes of samples without discarding
+// older ones outright.
+const quantileDecayAlpha = 0.015
+
+// decayedSample is one entry in quantileEstimator's priority-sampling heap:
+// value is the observation, priority is its forward-decay weight divided by
+// a random draw, per Cormode et al.'s forward-decaying reservoir algorithm.
+type decayedSample struct {
+	priority float64
+	value    float64
+}
+
+// sampleHeap is a min-heap of decayedSample ordered by priority, so the
+// lowest-priority (least relevant) sample is always at the root and can be
+// evicted in O(log n) when a higher-priority sample arrives.
+type sampleHeap []decayedSample
+
+func (h sampleHeap) Len() int            { return len(h) }
+func (h sampleHeap) Less(i, j int) bool  { return h[i].priority < h[j].priority }
+func (h sampleHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *sampleHeap) Push(x interface{}) { *h = append(*h, x.(decayedSample)) }
+func (h *sampleHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// quantileEstimator is an exponentially-decaying quantile estimator backed
+// by a bounded reservoir of up to quantileReservoirSize samples. Each sample
+// is weighted by how recently it arrived (quantileDecayAlpha), so once the
+// reservoir is full, newly arriving samples preferentially evict old,
+// low-weight ones instead of evicting uniformly at random - recent activity
+// dominates the resulting quantiles the way Dropwizard's
+// ExponentiallyDecayingReservoir does.
+type quantileEstimator struct {
+	lock      sync.Mutex
+	heap      sampleHeap
+	startTime time.Time
+}
+
+func newQuantileEstimator() *quantileEstimator {
+	return &quantileEstimator{startTime: time.Now()}
+}
+
+func (this *quantileEstimator) Add(value float64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	now := time.Now()
+	elapsed := now.Sub(this.startTime).Seconds()
+	// Move the forward-decay landmark up to now once it is an hour old.
+	// Scaling every priority by the same factor keeps the heap order, and
+	// keeps math.Exp(quantileDecayAlpha*elapsed) far from overflowing to
+	// +Inf, where new samples could no longer outrank old ones.
+	if elapsed > 3600 {
+		scale := math.Exp(-quantileDecayAlpha * elapsed)
+		for i := range this.heap {
+			this.heap[i].priority *= scale
+		}
+		this.startTime = now
+		elapsed = 0
+	}
+	priority := math.Exp(quantileDecayAlpha*elapsed) / rand.Float64()
+	sample := decayedSample{priority: priority, value: value}
+
+	if len(this.heap) < quantileReservoirSize {
+		heap.Push(&this.heap, sample)
+		return
+	}
+	// Only replace the lowest-priority sample if the new one outranks it;
+	// otherwise an old, already-decayed sample stays and the new one is
+	// dropped, same as a true forward-decaying reservoir of fixed size.
+	if sample.priority > this.heap[0].priority {
+		this.heap[0] = sample
+		heap.Fix(&this.heap, 0)
+	}
+}
+
+// Quantile returns the q-th quantile (0 <= q <= 1) of the samples currently
+// in the reservoir, or 0 if no samples have been recorded yet.
+func (this *quantileEstimator) Quantile(q float64) float64 {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	n := len(this.heap)
+	if n == 0 {
+		return 0
+	}
+	sorted := make([]float64, n)
+	for i, s := range this.heap {
+		sorted[i] = s.value
+	}
+	sort.Float64s(sorted)
+	idx := int(q * float64(n-1))
+	return sorted[idx]
+}
@@ -0,0 +1,10 @@
+package pool
+
+import "time"
+
+// currentTimeMillis returns the current time as a Unix millisecond
+// timestamp, the unit PooledObject and ObjectPool use throughout for
+// timeouts and idle/active time bookkeeping.
+func currentTimeMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}